@@ -0,0 +1,209 @@
+package sima
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the real-time source that drives [TimeMachine.run], so the
+// TimeMachine can be tested against a virtual clock instead of the wall
+// clock. See [SystemClock] for normal operation and [SimulatedClock] for
+// deterministic tests.
+type Clock interface {
+	// Now returns the clock's current time.
+	Now() time.Time
+	// NewTicker returns a Ticker that delivers ticks every d.
+	NewTicker(d time.Duration) Ticker
+	// AfterFunc schedules f to run after d has elapsed on the clock and
+	// returns a handle to cancel it.
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+// Ticker is the subset of *time.Ticker's behavior that [Clock] needs to
+// expose, so it can be backed by either the wall clock or a [SimulatedClock].
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// ClockTimer is a handle returned by [Clock.AfterFunc]. It is named to avoid
+// colliding with the simulation-time [Timer] returned by
+// [TimeMachine.Schedule].
+type ClockTimer interface {
+	Stop() bool
+}
+
+// SystemClock is a [Clock] backed by the real wall clock; it is the Clock
+// used by [NewTimeMachine].
+type SystemClock struct{}
+
+// Now returns time.Now().
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// NewTicker returns a Ticker backed by time.NewTicker.
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return &systemTicker{ticker: time.NewTicker(d)}
+}
+
+// AfterFunc schedules f via time.AfterFunc.
+func (SystemClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return time.AfterFunc(d, f)
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *systemTicker) C() <-chan time.Time { return t.ticker.C }
+func (t *systemTicker) Stop()               { t.ticker.Stop() }
+
+// SimulatedClock is a [Clock] whose time only advances when the test
+// explicitly calls [SimulatedClock.Advance]. Due tickers and timers fire
+// synchronously and deterministically, in time order, as part of that call.
+// This removes the need for time.Sleep in tests that exercise
+// [TimeMachine.Pause], [TimeMachine.Resume] or [TimeMachine.SetSpeed].
+type SimulatedClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*simulatedTicker
+	timers  []*simulatedClockTimer
+}
+
+// NewSimulatedClock creates a SimulatedClock starting at the Unix epoch.
+func NewSimulatedClock() *SimulatedClock {
+	return &SimulatedClock{now: time.Unix(0, 0)}
+}
+
+// Now returns the SimulatedClock's current simulated time.
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker registers a ticker that fires every d of simulated time.
+func (c *SimulatedClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &simulatedTicker{clock: c, period: d, next: c.now.Add(d), ch: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// AfterFunc registers f to run once the clock has advanced past d.
+func (c *SimulatedClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &simulatedClockTimer{clock: c, fireAt: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the simulated clock forward by d, firing any tickers and
+// AfterFunc timers that became due in the meantime, in time order.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+
+	type due struct {
+		at time.Time
+		fn func(time.Time)
+	}
+	var fired []due
+	for _, t := range c.tickers {
+		for !t.next.After(target) {
+			at := t.next
+			fired = append(fired, due{at: at, fn: func(at time.Time) { t.fire(at) }})
+			t.next = t.next.Add(t.period)
+		}
+	}
+	for _, t := range c.timers {
+		if t.stopped || t.fireAt.After(target) {
+			continue
+		}
+		at := t.fireAt
+		t.stopped = true //an AfterFunc fires at most once.
+		f := t.f
+		fired = append(fired, due{at: at, fn: func(time.Time) { f() }})
+	}
+	sort.Slice(fired, func(i, j int) bool { return fired[i].at.Before(fired[j].at) })
+	c.now = target
+	c.mu.Unlock()
+
+	for _, d := range fired {
+		d.fn(d.at)
+	}
+	if len(fired) > 0 {
+		// Give any goroutine consuming a ticker/timer through run() a chance
+		// to process what was just delivered before Advance returns, so
+		// callers can assert on the consequences immediately afterwards
+		// instead of having to sleep.
+		runtime.Gosched()
+	}
+}
+
+type simulatedTicker struct {
+	clock  *SimulatedClock
+	period time.Duration
+	next   time.Time
+	mu     sync.Mutex // guards ch, which fire grows in place instead of dropping ticks.
+	ch     chan time.Time
+}
+
+func (t *simulatedTicker) C() <-chan time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ch
+}
+
+func (t *simulatedTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, other := range t.clock.tickers {
+		if other == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			break
+		}
+	}
+}
+
+// fire delivers at on the ticker's channel. A single Advance call can make
+// the same ticker due more than once (e.g. advancing 25ms with a 10ms
+// period); unlike a plain buffer-1 channel, which would silently drop every
+// tick but the first, fire grows ch in place so every due tick is
+// eventually delivered, in order, across successive C() reads.
+func (t *simulatedTicker) fire(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case t.ch <- at:
+		return
+	default:
+	}
+	grown := make(chan time.Time, cap(t.ch)*2)
+	close(t.ch)
+	for v := range t.ch {
+		grown <- v
+	}
+	grown <- at
+	t.ch = grown
+}
+
+type simulatedClockTimer struct {
+	clock   *SimulatedClock
+	fireAt  time.Time
+	f       func()
+	stopped bool
+}
+
+func (t *simulatedClockTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.stopped = true
+	return true
+}
@@ -0,0 +1,67 @@
+package sima
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulatedClockTicker(t *testing.T) {
+	clock := NewSimulatedClock()
+	ticker := clock.NewTicker(10 * time.Millisecond)
+	clock.Advance(25 * time.Millisecond)
+	ticks := 0
+loop:
+	for {
+		select {
+		case <-ticker.C():
+			ticks++
+		default:
+			break loop
+		}
+	}
+	if ticks != 2 {
+		t.Errorf("Expected 2 ticks after advancing 25ms with a 10ms period, got %v", ticks)
+	}
+	ticker.Stop()
+}
+
+func TestSimulatedClockAfterFunc(t *testing.T) {
+	clock := NewSimulatedClock()
+	fired := false
+	timer := clock.AfterFunc(10*time.Millisecond, func() {
+		fired = true
+	})
+	clock.Advance(5 * time.Millisecond)
+	if fired {
+		t.Error("Expected AfterFunc NOT to have fired yet")
+	}
+	clock.Advance(10 * time.Millisecond)
+	if !fired {
+		t.Error("Expected AfterFunc to have fired")
+	}
+	if timer.Stop() {
+		t.Error("Expected Stop to return false for an already-fired timer")
+	}
+}
+
+// TestTimeMachineWithSimulatedClock shows that pause/resume can be tested
+// deterministically by driving the TimeMachine with a SimulatedClock instead
+// of sleeping on the real wall clock.
+func TestTimeMachineWithSimulatedClock(t *testing.T) {
+	clock := NewSimulatedClock()
+	tm := NewTimeMachineWithClock(clock, 1.0, 10, 1)
+	done := false
+	tm.Schedule(50, func() {
+		done = true
+	})
+	tm.Start()
+	clock.Advance(20 * time.Millisecond)
+	if done {
+		t.Error("Expected event NOT to be executed before its time")
+	}
+	clock.Advance(40 * time.Millisecond)
+	if !done {
+		t.Error("Expected event to be executed once the clock passed its time")
+	}
+	tm.Stop()
+}
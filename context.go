@@ -0,0 +1,37 @@
+package sima
+
+import (
+	"context"
+	"errors"
+)
+
+// StartCtx starts the simulation like [TimeMachine.Start], but also watches
+// ctx: once ctx is cancelled, the simulation is stopped cleanly (the ticker
+// drained and the TimeMachine transitioned to STOPPED) and ctx.Err() is
+// returned.
+//
+// StartCtx blocks until ctx is cancelled, so callers typically run it in its
+// own goroutine, e.g. alongside an [errgroup.Group] or a signal handler, to
+// get a graceful shutdown story for free.
+func (tm *TimeMachine) StartCtx(ctx context.Context) error {
+	if err := tm.Start(); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	if err := tm.Stop(); err != nil && !errors.Is(err, ErrAlreadyStopped) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// ScheduleCtx behaves like [TimeMachine.Schedule], but passes ctx to f when
+// the event fires and skips running f entirely if ctx is already done by
+// then.
+func (tm *TimeMachine) ScheduleCtx(ctx context.Context, dt float64, f func(context.Context)) *Timer {
+	return tm.Schedule(dt, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		f(ctx)
+	})
+}
@@ -0,0 +1,46 @@
+package sima
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeMachineStartCtx(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tm.StartCtx(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond) // let the simulation start
+	cancel()
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected StartCtx to return context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected StartCtx to return after ctx was cancelled")
+	}
+	if tm.State() != STOPPED {
+		t.Errorf("Expected state to be STOPPED, got %v", tm.State())
+	}
+}
+
+func TestTimeMachineScheduleCtx(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 2)
+	tm.Start()
+	ctx, cancel := context.WithCancel(context.Background())
+	executed := false
+	tm.ScheduleCtx(ctx, 100, func(ctx context.Context) {
+		executed = true
+	})
+	cancel()
+	time.Sleep(200 * time.Millisecond) // wait for the event to have been skipped
+	if executed {
+		t.Errorf("Expected event NOT to be executed once its context was cancelled")
+	}
+	tm.Stop()
+}
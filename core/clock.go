@@ -0,0 +1,63 @@
+package core
+
+import "time"
+
+// Clock models a source of time, in ms, modeled after go-ethereum's
+// mclock.Clock. It lets code be written once against Clock and run either
+// against the real wall clock ([SystemClock]) or inside a sped-up, paused
+// or deterministically-ticked [TimeMachine] simulation, without change.
+type Clock interface {
+	// Now returns the current time in ms.
+	Now() float64
+	// Sleep blocks the calling goroutine until d ms have passed.
+	Sleep(d float64)
+	// After returns a channel on which the time is sent once d ms have passed.
+	After(d float64) <-chan float64
+	// AfterFunc schedules f to run after d ms and returns a [Timer] that can
+	// cancel or reschedule it before it fires.
+	AfterFunc(d float64, f func()) *Timer
+}
+
+// TimeMachine implements Clock against simulated time.
+var _ Clock = (*TimeMachine)(nil)
+
+// SystemClock implements [Clock] against the real wall clock, in ms since
+// the SystemClock was created.
+type SystemClock struct {
+	start time.Time
+}
+
+// NewSystemClock creates a SystemClock whose Now starts at 0.
+func NewSystemClock() *SystemClock {
+	return &SystemClock{start: time.Now()}
+}
+
+// Now implements [Clock].
+func (c *SystemClock) Now() float64 {
+	return float64(time.Since(c.start).Milliseconds())
+}
+
+// Sleep implements [Clock].
+func (c *SystemClock) Sleep(d float64) {
+	time.Sleep(time.Duration(d) * time.Millisecond)
+}
+
+// After implements [Clock].
+func (c *SystemClock) After(d float64) <-chan float64 {
+	ch := make(chan float64, 1)
+	time.AfterFunc(time.Duration(d)*time.Millisecond, func() {
+		ch <- c.Now()
+	})
+	return ch
+}
+
+// AfterFunc implements [Clock].
+func (c *SystemClock) AfterFunc(d float64, f func()) *Timer {
+	wt := time.AfterFunc(time.Duration(d)*time.Millisecond, f)
+	return &Timer{
+		stop: wt.Stop,
+		reset: func(d float64) bool {
+			return wt.Reset(time.Duration(d) * time.Millisecond)
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeMachineClockSleep(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	tm.Start()
+	woken := make(chan float64, 1)
+	go func() {
+		tm.Sleep(100)
+		woken <- tm.Now()
+	}()
+	select {
+	case wakeT := <-woken:
+		if wakeT < 100 {
+			t.Errorf("expected Sleep to wake at simulation time >= 100, got %v", wakeT)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Sleep did not return in time")
+	}
+	tm.Stop()
+}
+
+func TestTimeMachineClockAfterFunc(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	tm.Start()
+	fired := make(chan struct{}, 1)
+	var clock Clock = tm
+	clock.AfterFunc(50, func() {
+		fired <- struct{}{}
+	})
+	select {
+	case <-fired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("AfterFunc did not fire in time")
+	}
+	tm.Stop()
+}
+
+func TestSystemClock(t *testing.T) {
+	var clock Clock = NewSystemClock()
+	start := clock.Now()
+	clock.Sleep(10)
+	if clock.Now()-start < 10 {
+		t.Errorf("expected at least 10 ms to have passed, got %v", clock.Now()-start)
+	}
+}
@@ -11,5 +11,8 @@ We distinguish two different notions of time:
   - The simulation time; This is the time attached to the event. It is a float64 and is interpreted
     as the amount of milliseconds since the start of the simulation.
   - The real time: This is the normal time that you can read from your watch.
+
+[Clock] abstracts over these two notions of time so that the same code can
+run against the real wall clock ([SystemClock]) or a [TimeMachine].
 */
 package core
@@ -2,78 +2,281 @@ package core
 
 import (
 	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 )
 
+// eventState tracks the lifecycle of an Event for cancellation purposes.
+type eventState int32
+
+const (
+	eventPending eventState = iota
+	eventCancelled
+	eventFired
+)
+
+// queue is satisfied by any backing store usable as a TimeMachine's pending
+// event store (s. EventQueue, TimingWheelQueue).
+type queue interface {
+	add(event *Event)
+	remove(event *Event)
+	next() *Event
+	nextT() (t float64, ok bool)
+	all() []*Event
+}
+
 // Event represents a single Event in the simulation
 type Event struct {
-	t float64
-	f func()
+	t     float64
+	f     func()
+	seq   uint64 // assigned at Schedule time; breaks ties between equal t.
+	index int    // index of the event in the EventQueue heap; -1 if not in the queue.
+	state atomic.Int32
+	name  string // non-empty only for events scheduled via ScheduleNamed; required for Snapshot.
+	args  []byte
 }
 
-// EventQueue implements a priority queue for Events.
-type EventQueue []*Event
+// newEvent creates an Event not yet in any EventQueue.
+func newEvent(t float64, f func()) *Event {
+	return &Event{t: t, f: f, index: -1}
+}
 
-// Len returns the length of the event queue.
-func (eq EventQueue) Len() int { return len(eq) }
+// cancel marks the event as cancelled so it will be skipped instead of fired.
+// It returns true if the event was pending and is now cancelled, false if it
+// had already fired or was already cancelled.
+func (e *Event) cancel() bool {
+	return e.state.CompareAndSwap(int32(eventPending), int32(eventCancelled))
+}
 
-// Less returns true if the event at index i is less/earlier than the event at index j.
-func (eq EventQueue) Less(i, j int) bool {
-	return eq[i].t < eq[j].t
+// markFired transitions the event to the fired state if it is still pending.
+// It returns false if the event was cancelled before it could fire.
+func (e *Event) markFired() bool {
+	return e.state.CompareAndSwap(int32(eventPending), int32(eventFired))
+}
+
+// eventHeap implements container/heap.Interface over a slice of Events. It
+// carries no synchronization of its own: EventQueue and TimingWheelQueue
+// each wrap it with whatever locking they need.
+type eventHeap []*Event
+
+// Len returns the length of the event heap.
+func (eh eventHeap) Len() int { return len(eh) }
+
+// Less returns true if the event at index i is less/earlier than the event
+// at index j. Events with equal t are ordered by their Schedule-time
+// sequence number, so equal-t events always fire in insertion order instead
+// of heap-arbitrary order.
+func (eh eventHeap) Less(i, j int) bool {
+	if eh[i].t != eh[j].t {
+		return eh[i].t < eh[j].t
+	}
+	return eh[i].seq < eh[j].seq
 }
 
 // Swap swaps the events at index i and j.
 // Only to be used by the heap package.
 // Never call it directly.
-func (eq EventQueue) Swap(i, j int) {
-	eq[i], eq[j] = eq[j], eq[i]
+func (eh eventHeap) Swap(i, j int) {
+	eh[i], eh[j] = eh[j], eh[i]
+	eh[i].index = i
+	eh[j].index = j
 }
 
-// Push adds an event to the event queue.
+// Push adds an event to the event heap.
 // Only to be used by the heap package.
 // Never call it directly.
-func (eq *EventQueue) Push(x interface{}) {
+func (eh *eventHeap) Push(x interface{}) {
 	event := x.(*Event)
-	*eq = append(*eq, event)
+	event.index = len(*eh)
+	*eh = append(*eh, event)
 }
 
-// Pop removes and returns the last event from the event queue.
+// Pop removes and returns the last event from the event heap.
 // Only to be used by the heap package.
 // Never call it directly.
-func (eq *EventQueue) Pop() interface{} {
-	old := *eq
+func (eh *eventHeap) Pop() interface{} {
+	old := *eh
 	n := len(old)
 	event := old[n-1]
 	old[n-1] = nil // avoid memory leak
-	*eq = old[0 : n-1]
+	event.index = -1
+	*eh = old[0 : n-1]
 	return event
 }
 
-// tNext returns the time of the next event in ms.
-// If there are no more events then ok is false.
-func (eq EventQueue) nextT() (t float64, ok bool) {
-	if len(eq) > 0 {
+// nextT returns the time of the earliest event in the heap, if any.
+func (eh eventHeap) nextT() (t float64, ok bool) {
+	if len(eh) > 0 {
 		ok = true
-		t = eq[0].t
+		t = eh[0].t
 	}
 	return t, ok
 }
 
+// ErrQueueClosed is returned by NextBlocking and NextBlockingUntil once the
+// EventQueue they were called on has been closed.
+var ErrQueueClosed = errors.New("core: event queue is closed")
+
+// EventQueue implements a priority queue for Events, safe for concurrent
+// use by multiple goroutines.
+//
+// Each Event tracks its own index in the heap (maintained by eventHeap's
+// Swap, Push and Pop), which lets a cancelled event be removed in O(log n)
+// via heap.Remove instead of waiting to be skipped on Pop.
+type EventQueue struct {
+	mu      sync.Mutex
+	heap    eventHeap
+	waiters []chan struct{} // parked NextBlocking/NextBlockingUntil callers, woken by Add/Close.
+	closed  bool
+}
+
 // NewEventQueue creates a new EventQueue.
 func NewEventQueue() *EventQueue {
 	eq := &EventQueue{}
-	heap.Init(eq)
+	heap.Init(&eq.heap)
 	return eq
 }
 
-// add adds a new event to the queue.
+// Len returns the number of events currently in the queue.
+func (eq *EventQueue) Len() int {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	return eq.heap.Len()
+}
+
+// wakeLocked notifies every parked waiter that the queue state changed, so
+// each can re-check whether it is now satisfied. Callers hold eq.mu.
+func (eq *EventQueue) wakeLocked() {
+	for _, w := range eq.waiters {
+		close(w)
+	}
+	eq.waiters = nil
+}
+
+// add adds a new event to the queue. An event that was cancelled before it
+// could be added is silently dropped.
 func (eq *EventQueue) add(event *Event) {
-	heap.Push(eq, event)
+	eq.mu.Lock()
+	if event.state.Load() != int32(eventCancelled) {
+		heap.Push(&eq.heap, event)
+	}
+	eq.wakeLocked()
+	eq.mu.Unlock()
+}
+
+// Add is the exported form of add, for use by callers driving an EventQueue
+// directly instead of through a TimeMachine (s. NextBlocking).
+func (eq *EventQueue) Add(event *Event) {
+	eq.add(event)
 }
 
 // next retrieves and removes the next event from the queue.
 func (eq *EventQueue) next() *Event {
-	if eq.Len() == 0 {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	if eq.heap.Len() == 0 {
 		return nil
 	}
-	return heap.Pop(eq).(*Event)
+	return heap.Pop(&eq.heap).(*Event)
+}
+
+// remove removes event from the queue in O(log n), if it is still present.
+// It is a no-op if event has already fired or was never added.
+func (eq *EventQueue) remove(event *Event) {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	if event.index < 0 {
+		return
+	}
+	heap.Remove(&eq.heap, event.index)
+}
+
+// nextT returns the time of the next event in ms.
+// If there are no more events then ok is false.
+func (eq *EventQueue) nextT() (t float64, ok bool) {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	return eq.heap.nextT()
+}
+
+// all returns every event currently in the queue, in no particular order.
+func (eq *EventQueue) all() []*Event {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	out := make([]*Event, len(eq.heap))
+	copy(out, eq.heap)
+	return out
+}
+
+// NextBlocking retrieves and removes the next event from the queue, parking
+// the calling goroutine until one is available. It lets a subsystem outside
+// a TimeMachine (e.g. a network transport simulator feeding packets in)
+// consume events as they're scheduled, without busy-polling nextT.
+//
+// It returns ctx.Err() if ctx is done before an event becomes available, or
+// ErrQueueClosed if the queue is closed while waiting.
+func (eq *EventQueue) NextBlocking(ctx context.Context) (*Event, error) {
+	for {
+		eq.mu.Lock()
+		if eq.closed {
+			eq.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		if eq.heap.Len() > 0 {
+			ev := heap.Pop(&eq.heap).(*Event)
+			eq.mu.Unlock()
+			return ev, nil
+		}
+		w := make(chan struct{})
+		eq.waiters = append(eq.waiters, w)
+		eq.mu.Unlock()
+		select {
+		case <-w:
+			// Queue state changed; loop around and re-check it.
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// NextBlockingUntil retrieves and removes the next event due at or before
+// simT, parking the calling goroutine until one becomes available. It suits
+// a driver that only wants to advance the queue up to a known simulation
+// time, rather than consuming whatever is next regardless of how far out it
+// lies (s. NextBlocking).
+//
+// It returns ErrQueueClosed if the queue is closed while waiting.
+func (eq *EventQueue) NextBlockingUntil(simT float64) (*Event, error) {
+	for {
+		eq.mu.Lock()
+		if eq.closed {
+			eq.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		if t, ok := eq.heap.nextT(); ok && t <= simT {
+			ev := heap.Pop(&eq.heap).(*Event)
+			eq.mu.Unlock()
+			return ev, nil
+		}
+		w := make(chan struct{})
+		eq.waiters = append(eq.waiters, w)
+		eq.mu.Unlock()
+		<-w
+	}
+}
+
+// Close disables the queue for blocking consumers: every call to
+// NextBlocking or NextBlockingUntil currently parked, or made afterwards,
+// returns ErrQueueClosed. Close is idempotent and safe to call concurrently
+// with NextBlocking/NextBlockingUntil/Add.
+func (eq *EventQueue) Close() {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	if eq.closed {
+		return
+	}
+	eq.closed = true
+	eq.wakeLocked()
 }
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventQueueNextBlockingWakesOnAdd(t *testing.T) {
+	eq := NewEventQueue()
+	done := make(chan *Event, 1)
+	go func() {
+		ev, err := eq.NextBlocking(context.Background())
+		if err != nil {
+			t.Errorf("unexpected error from NextBlocking: %v", err)
+		}
+		done <- ev
+	}()
+	time.Sleep(20 * time.Millisecond) // let NextBlocking park before adding.
+	want := newEvent(10, func() {})
+	eq.Add(want)
+	select {
+	case got := <-done:
+		if got != want {
+			t.Errorf("expected NextBlocking to return the added event")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("NextBlocking did not wake up in time")
+	}
+}
+
+func TestEventQueueNextBlockingRespectsContext(t *testing.T) {
+	eq := NewEventQueue()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := eq.NextBlocking(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestEventQueueNextBlockingUntil(t *testing.T) {
+	eq := NewEventQueue()
+	eq.Add(newEvent(100, func() {}))
+	done := make(chan *Event, 1)
+	go func() {
+		ev, err := eq.NextBlockingUntil(50)
+		if err != nil {
+			t.Errorf("unexpected error from NextBlockingUntil: %v", err)
+		}
+		done <- ev
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected NextBlockingUntil(50) not to return an event due at t=100")
+	case <-time.After(50 * time.Millisecond):
+	}
+	eq.Add(newEvent(40, func() {}))
+	select {
+	case ev := <-done:
+		if ev.t != 40 {
+			t.Errorf("expected the event due at t=40, got t=%v", ev.t)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("NextBlockingUntil did not wake up in time")
+	}
+}
+
+func TestEventQueueCloseIsIdempotentAndWakesWaiters(t *testing.T) {
+	eq := NewEventQueue()
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := eq.NextBlocking(context.Background())
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // let both goroutines park before closing.
+	eq.Close()
+	eq.Close() // must not panic or double-close a waiter channel.
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrQueueClosed) {
+				t.Errorf("expected ErrQueueClosed, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("NextBlocking did not return after Close")
+		}
+	}
+	if _, err := eq.NextBlocking(context.Background()); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("expected ErrQueueClosed after Close, got %v", err)
+	}
+}
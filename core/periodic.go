@@ -0,0 +1,50 @@
+package core
+
+import "sync/atomic"
+
+// Ticker delivers periodic events, like [time.Ticker]. It is created by
+// [TimeMachine.ScheduleEvery].
+type Ticker struct {
+	tm      *TimeMachine
+	f       func()
+	period  float64
+	stopped atomic.Bool
+	timer   *Timer
+}
+
+// ScheduleEvery schedules f to run every period ms of simulation time,
+// starting period ms after the current simulation time. Each re-scheduling
+// is anchored to the simulation time of the previous firing rather than to
+// wall-clock time at fire time, so the cadence never drifts.
+//
+// The returned Ticker's Stop cancels the pending firing and prevents any
+// further ones; it is safe to call Stop from within f itself.
+func (tm *TimeMachine) ScheduleEvery(period float64, f func()) *Ticker {
+	tk := &Ticker{tm: tm, f: f, period: period}
+	tk.timer = tm.Schedule(period, tk.fire)
+	return tk
+}
+
+// fire runs the ticker's function and, unless the ticker has been stopped
+// in the meantime, re-arms it period ms later.
+func (tk *Ticker) fire() {
+	tk.f()
+	if tk.stopped.Load() {
+		return
+	}
+	tk.timer = tk.tm.Schedule(tk.period, tk.fire)
+}
+
+// Stop cancels the ticker, preventing any further firings.
+func (tk *Ticker) Stop() {
+	tk.stopped.Store(true)
+	tk.timer.Stop()
+}
+
+// ScheduleCron schedules f to run once at each absolute simulation time in
+// times. The times need not be sorted or evenly spaced.
+func (tm *TimeMachine) ScheduleCron(times []float64, f func()) {
+	for _, at := range times {
+		tm.Schedule(at-tm.T(), f)
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeMachineScheduleEvery(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	tm.Start()
+	count := 0
+	ticker := tm.ScheduleEvery(10, func() {
+		count++
+	})
+	time.Sleep(100 * time.Millisecond) // let several periods elapse
+	ticker.Stop()
+	countAfterStop := count
+	time.Sleep(50 * time.Millisecond)
+	if count < 3 {
+		t.Errorf("Expected the ticker to have fired several times, got %v", count)
+	}
+	if count != countAfterStop {
+		t.Errorf("Expected no more firings after Stop, got %v additional", count-countAfterStop)
+	}
+	tm.Stop()
+}
+
+func TestTimeMachineScheduleCron(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	tm.Start()
+	fired := make(map[float64]bool)
+	tm.ScheduleCron([]float64{30, 10, 50}, func() {
+		fired[tm.Now()] = true
+	})
+	time.Sleep(100 * time.Millisecond)
+	for _, at := range []float64{10, 30, 50} {
+		if !fired[at] {
+			t.Errorf("Expected an event to have fired at t=%v", at)
+		}
+	}
+	tm.Stop()
+}
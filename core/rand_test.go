@@ -0,0 +1,43 @@
+package core
+
+import "testing"
+
+func TestNewTimeMachineSeeded(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 100)
+	if tm.Rand() != nil {
+		t.Error("Expected Rand to be nil for a TimeMachine not created via NewTimeMachineSeeded")
+	}
+
+	a := NewTimeMachineSeeded(1.0, 10, 100, 42)
+	b := NewTimeMachineSeeded(1.0, 10, 100, 42)
+	if a.Rand() == nil || b.Rand() == nil {
+		t.Fatal("Expected Rand to be non-nil for a seeded TimeMachine")
+	}
+	for i := 0; i < 10; i++ {
+		x, y := a.Rand().Uint64(), b.Rand().Uint64()
+		if x != y {
+			t.Errorf("Expected identical seeds to produce identical streams, got %v != %v", x, y)
+		}
+	}
+}
+
+func TestEventQueueTieBreaking(t *testing.T) {
+	eq := NewEventQueue()
+	var order []int
+	for i := 0; i < 5; i++ {
+		i := i
+		eq.add(&Event{t: 10.0, seq: uint64(i), f: func() { order = append(order, i) }, index: -1})
+	}
+	for {
+		ev := eq.next()
+		if ev == nil {
+			break
+		}
+		ev.f()
+	}
+	for i, got := range order {
+		if got != i {
+			t.Errorf("Expected equal-t events to fire in insertion order, got %v at position %v", got, i)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package core
+
+import "sync"
+
+// Registry maps names to event constructors so that events scheduled via
+// [TimeMachine.ScheduleNamed] can be serialized by [TimeMachine.Snapshot]
+// and reconstructed by [RestoreTimeMachine] — a raw func() closure cannot
+// itself be serialized.
+type Registry struct {
+	mu        sync.Mutex
+	factories map[string]func(args []byte) func()
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]func(args []byte) func())}
+}
+
+// Register associates name with factory, which builds the event's function
+// from its serialized args when the event is scheduled via ScheduleNamed or
+// reconstructed by RestoreTimeMachine.
+func (r *Registry) Register(name string, factory func(args []byte) func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[name] = factory
+}
+
+// create builds the function registered under name, if any.
+func (r *Registry) create(name string, args []byte) (func(), bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	factory, ok := r.factories[name]
+	if !ok {
+		return nil, false
+	}
+	return factory(args), true
+}
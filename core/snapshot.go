@@ -0,0 +1,93 @@
+package core
+
+import "fmt"
+
+// Snapshot captures a TimeMachine's simulation time, speed, state and
+// pending event queue, as produced by [TimeMachine.Snapshot] and consumed
+// by [RestoreTimeMachine]. It is a plain data structure with exported
+// fields, serializable with encoding/json or encoding/gob.
+type Snapshot struct {
+	Time   float64
+	Speed  float64
+	State  TMState
+	Events []EventSnapshot
+}
+
+// EventSnapshot captures one pending, named event.
+type EventSnapshot struct {
+	T    float64
+	Name string
+	Args []byte
+}
+
+// ScheduleNamed schedules the event built by registry for name from args to
+// run dt ms after the current simulation time. If dt is negative, it will
+// be reset to 0.0.
+//
+// Unlike Schedule, the resulting event is snapshotable: [TimeMachine.Snapshot]
+// records its name and args instead of requiring the func() itself to be
+// serialized. It returns an error if name is not registered in tm's
+// [Registry] (s. [NewTimeMachineWithRegistry]).
+func (tm *TimeMachine) ScheduleNamed(dt float64, name string, args []byte) (*Timer, error) {
+	if tm.registry == nil {
+		return nil, fmt.Errorf("core: TimeMachine has no Registry; create it via NewTimeMachineWithRegistry")
+	}
+	f, ok := tm.registry.create(name, args)
+	if !ok {
+		return nil, fmt.Errorf("core: no event registered under name %q", name)
+	}
+	if dt < 0 {
+		dt = 0.0
+	}
+	ev := newEvent(tm.T()+dt, f)
+	ev.seq = tm.seq.Add(1)
+	ev.name = name
+	ev.args = args
+	tm.events <- ev
+	return newEventTimer(tm, ev, f), nil
+}
+
+// Snapshot captures tm's current time, speed, state and pending event queue.
+//
+// Only events scheduled via ScheduleNamed can be captured, since a raw
+// func() closure cannot be serialized: a pending event scheduled via
+// Schedule causes Snapshot to return an error. Events still sitting in the
+// internal events channel (scheduled just before Snapshot is called, and
+// not yet drained into the queue by the run goroutine) are not captured;
+// call Snapshot only while tm is PAUSED or STOPPED to get a complete view.
+func (tm *TimeMachine) Snapshot() (*Snapshot, error) {
+	s := &Snapshot{
+		Time:  tm.T(),
+		Speed: tm.Speed(),
+		State: tm.State(),
+	}
+	for _, ev := range tm.eventQueue.all() {
+		if ev.state.Load() != int32(eventPending) {
+			continue //a cancelled event a TimingWheelQueue has not yet discarded.
+		}
+		if ev.name == "" {
+			return nil, fmt.Errorf("core: cannot snapshot an unnamed event scheduled via Schedule (t=%v); use ScheduleNamed instead", ev.t)
+		}
+		s.Events = append(s.Events, EventSnapshot{T: ev.t, Name: ev.name, Args: ev.args})
+	}
+	return s, nil
+}
+
+// RestoreTimeMachine creates a new TimeMachine from a Snapshot previously
+// produced by [TimeMachine.Snapshot], re-creating each pending event's
+// function by looking up its name in registry. cycleTime is as in
+// [NewTimeMachine]; eventChanSize must be at least len(s.Events), since all
+// restored events are scheduled before the returned TimeMachine is started.
+//
+// The restored TimeMachine is returned in the IDLE state, ready for Start;
+// s.State only records the state the snapshot was taken in.
+func RestoreTimeMachine(s *Snapshot, eventChanSize int, cycleTime int, registry *Registry) (*TimeMachine, error) {
+	tm := NewTimeMachineWithRegistry(s.Speed, eventChanSize, cycleTime, registry)
+	tm.setT(s.Time)
+	for _, es := range s.Events {
+		if _, err := tm.ScheduleNamed(es.T-s.Time, es.Name, es.Args); err != nil {
+			return nil, err
+		}
+	}
+	return tm, nil
+}
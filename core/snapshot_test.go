@@ -0,0 +1,94 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func pingPongRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("noop", func(args []byte) func() {
+		return func() {}
+	})
+	return r
+}
+
+func TestScheduleNamedRequiresRegistry(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 1)
+	if _, err := tm.ScheduleNamed(10, "noop", nil); err == nil {
+		t.Error("Expected an error scheduling a named event without a Registry")
+	}
+}
+
+func TestScheduleNamedUnknownName(t *testing.T) {
+	tm := NewTimeMachineWithRegistry(1.0, 10, 1, pingPongRegistry())
+	if _, err := tm.ScheduleNamed(10, "does-not-exist", nil); err == nil {
+		t.Error("Expected an error scheduling an unregistered event name")
+	}
+}
+
+func TestSnapshotRejectsUnnamedEvents(t *testing.T) {
+	tm := NewTimeMachineWithRegistry(10.0, 10, 1, pingPongRegistry())
+	tm.Schedule(1000, func() {})
+	tm.Start()
+	defer tm.Stop()
+	time.Sleep(50 * time.Millisecond) // let the event be drained into the queue
+	if _, err := tm.Snapshot(); err == nil {
+		t.Error("Expected Snapshot to reject a pending event scheduled via Schedule")
+	}
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	registry := NewRegistry()
+	fired := make(chan string, 10)
+	registry.Register("tick", func(args []byte) func() {
+		name := string(args)
+		return func() { fired <- name }
+	})
+
+	tm := NewTimeMachineWithRegistry(10.0, 10, 1, registry)
+	tm.ScheduleNamed(5000, "tick", []byte("a"))
+	tm.ScheduleNamed(10000, "tick", []byte("b"))
+	tm.Start()
+	time.Sleep(50 * time.Millisecond) // let the events be drained into the queue
+	tm.Pause()
+
+	snap, err := tm.Snapshot()
+	if err != nil {
+		t.Fatalf("Expected Snapshot to succeed, got %v", err)
+	}
+	tm.Stop()
+
+	blob, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("Expected Snapshot to be JSON-serializable, got %v", err)
+	}
+	var restored Snapshot
+	if err := json.Unmarshal(blob, &restored); err != nil {
+		t.Fatalf("Expected Snapshot to round-trip through JSON, got %v", err)
+	}
+
+	tm2, err := RestoreTimeMachine(&restored, 10, 1, registry)
+	if err != nil {
+		t.Fatalf("Expected RestoreTimeMachine to succeed, got %v", err)
+	}
+	if tm2.State() != IDLE {
+		t.Errorf("Expected restored TimeMachine to be IDLE, got %v", tm2.State())
+	}
+	tm2.Start()
+	defer tm2.Stop()
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-fired:
+			seen[name] = true
+		case <-time.After(2 * time.Second):
+			t.Fatal("restored events did not fire in time")
+		}
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Errorf("Expected both restored events to fire, got %v", seen)
+	}
+}
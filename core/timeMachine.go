@@ -2,8 +2,11 @@ package core
 
 import (
 	"log"
+	rand "math/rand/v2"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -41,13 +44,17 @@ func (s TMState) String() string {
 //The commands are put into the cmds channel by the respective methods.
 
 type TimeMachine struct {
-	eventQueue *EventQueue
+	mu         sync.RWMutex //guards t, state and speed below against the run goroutine.
+	eventQueue queue
 	t          float64 //the actual simulation time in ms.
 	cycleTime  int     //every cycleTime [ms] the time machine will check for new events.
 	state      TMState
 	speed      float64 //ratio between simulation and real time.
 	cmds       chan string
 	events     chan *Event
+	seq        atomic.Uint64 // next sequence number to assign at Schedule time.
+	rng        *rand.Rand    // non-nil only for a TimeMachine created via NewTimeMachineSeeded.
+	registry   *Registry     // non-nil only for a TimeMachine created via NewTimeMachineWithRegistry.
 }
 
 // NewTimeMachine creates a reference to a new TimeMachine.
@@ -66,27 +73,128 @@ func NewTimeMachine(speed float64, eventChanSize int, cycleTime int) *TimeMachin
 	}
 }
 
+// NewTimeMachineSeeded creates a reference to a new TimeMachine whose [TimeMachine.Rand]
+// draws from a reproducible stream seeded with seed, so that simulation
+// models relying on randomness produce identical event orderings and
+// results across runs. The remaining parameters are as in [NewTimeMachine].
+func NewTimeMachineSeeded(speed float64, eventChanSize int, cycleTime int, seed uint64) *TimeMachine {
+	tm := NewTimeMachine(speed, eventChanSize, cycleTime)
+	tm.rng = rand.New(rand.NewPCG(seed, seed))
+	return tm
+}
+
+// Rand returns the TimeMachine's reproducible random source, or nil if it
+// was not created via [NewTimeMachineSeeded].
+func (tm *TimeMachine) Rand() *rand.Rand {
+	return tm.rng
+}
+
+// NewTimeMachineWithQueue creates a reference to a new TimeMachine backed by
+// q instead of the default heap-based EventQueue. Use [TimingWheelQueue]
+// in place of the default when holding very large numbers of pending
+// events (s. [NewTimingWheelQueue]). The remaining parameters are as in
+// [NewTimeMachine].
+func NewTimeMachineWithQueue(speed float64, eventChanSize int, cycleTime int, q queue) *TimeMachine {
+	tm := NewTimeMachine(speed, eventChanSize, cycleTime)
+	tm.eventQueue = q
+	return tm
+}
+
+// NewTimeMachineWithRegistry creates a reference to a new TimeMachine whose
+// [TimeMachine.ScheduleNamed] resolves event names against registry, so
+// that its pending events can be captured by [TimeMachine.Snapshot] and
+// reconstructed by [RestoreTimeMachine]. The remaining parameters are as in
+// [NewTimeMachine].
+func NewTimeMachineWithRegistry(speed float64, eventChanSize int, cycleTime int, registry *Registry) *TimeMachine {
+	tm := NewTimeMachine(speed, eventChanSize, cycleTime)
+	tm.registry = registry
+	return tm
+}
+
 // T returns the current simulation time of the time machine.
 func (tm *TimeMachine) T() float64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.t
 }
 
 func (tm *TimeMachine) State() TMState {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.state
 }
 
 func (tm *TimeMachine) Speed() float64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.speed
 }
 
+// setState transitions the TimeMachine to s. It is called by Start before
+// the run goroutine is launched, and by the run goroutine afterwards.
+func (tm *TimeMachine) setState(s TMState) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.state = s
+}
+
+// setSpeed updates the simulation speed. It is only safe to call from the
+// run goroutine.
+func (tm *TimeMachine) setSpeed(speed float64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.speed = speed
+}
+
+// setT advances the simulation time to t. It is called by Step and, before
+// the TimeMachine is started, by RestoreTimeMachine.
+func (tm *TimeMachine) setT(t float64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.t = t
+}
+
 // Schedule schedules a function f to be executed at dt after the current simulation time.
 // If dt is negative, it will be reset to 0.0.
-func (tm *TimeMachine) Schedule(dt float64, f func()) {
+//
+// It returns a [Timer] handle that can be used to cancel or reschedule the event
+// before it fires.
+func (tm *TimeMachine) Schedule(dt float64, f func()) *Timer {
 	if dt < 0 {
 		log.Printf("dt = %v < 0. Will be reset to 0.0", dt)
 		dt = 0.0
 	}
-	tm.events <- (&Event{tm.t + dt, f})
+	ev := newEvent(tm.T()+dt, f)
+	ev.seq = tm.seq.Add(1)
+	tm.events <- ev
+	return newEventTimer(tm, ev, f)
+}
+
+// Now implements [Clock]. It is an alias for T, kept so TimeMachine
+// satisfies Clock's method set.
+func (tm *TimeMachine) Now() float64 {
+	return tm.T()
+}
+
+// Sleep implements [Clock]. It blocks the calling goroutine until the
+// TimeMachine's simulation time has advanced by at least d ms.
+func (tm *TimeMachine) Sleep(d float64) {
+	<-tm.After(d)
+}
+
+// After implements [Clock]. It returns a channel on which the simulation
+// time is sent once it has advanced by at least d ms.
+func (tm *TimeMachine) After(d float64) <-chan float64 {
+	c := make(chan float64, 1)
+	tm.Schedule(d, func() {
+		c <- tm.T()
+	})
+	return c
+}
+
+// AfterFunc implements [Clock]. It is equivalent to Schedule.
+func (tm *TimeMachine) AfterFunc(d float64, f func()) *Timer {
+	return tm.Schedule(d, f)
 }
 
 func (tm *TimeMachine) Pause() {
@@ -114,11 +222,10 @@ func (tm *TimeMachine) SetSpeed(speed float64) {
 // Start starts the simulation.
 // It returns true if the simulation was started successfully.
 func (tm *TimeMachine) Start() (success bool) {
-	if tm.state != IDLE {
-
+	if tm.State() != IDLE {
 		return false
 	}
-	tm.state = RUNNING
+	tm.setState(RUNNING)
 	go tm.run()
 	return true
 }
@@ -148,27 +255,27 @@ main:
 			select {
 			case cmd := <-tm.cmds:
 				if cmd == "PAUSE" {
-					if tm.state != RUNNING {
+					if tm.State() != RUNNING {
 						log.Printf("TimeMachine is not running. Ignoring PAUSE command.")
 					} else {
-						tm.state = PAUSED
+						tm.setState(PAUSED)
 						tReal_pause = tReal
 					}
 				} else if cmd == "STOP" {
 					ticker.Stop()
-					tm.state = STOPPED
+					tm.setState(STOPPED)
 					break main
 				} else if cmd == "RESUME" {
-					if tm.state != PAUSED {
+					if tm.State() != PAUSED {
 						log.Printf("TimeMachine is not paused. Ignoring RESUME command.")
 					} else {
-						tm.state = RUNNING
+						tm.setState(RUNNING)
 						tReal_offset += tReal.Sub(tReal_pause).Milliseconds()
 					}
 				} else if strings.Fields(cmd)[0] == "SetSpeed" {
 					speed, err := strconv.ParseFloat(strings.Fields(cmd)[1], 64)
 					if err == nil {
-						tm.speed = speed
+						tm.setSpeed(speed)
 					} else {
 						log.Printf("Could not convert the speed in command: %s", cmd)
 					}
@@ -181,14 +288,15 @@ main:
 		}
 		//run events from the "past" of the real time
 		dtReal := tReal.Sub(tReal_start).Milliseconds() - tReal_offset //time passed in ms since start minus offset
-		if tm.state == RUNNING {
+		if tm.State() == RUNNING {
+			speed := tm.Speed()
 			for {
 				tNext, ok := tm.eventQueue.nextT()
 				if !ok {
-					return
+					break //no more events
 				}
-				if tNext < float64(dtReal)*tm.speed {
-					//log.Printf("t: %v, tNext: %v,  dtReal: %v, tReal: %v", tm.t, tNext, dtReal, tReal)
+				if tNext < float64(dtReal)*speed {
+					//log.Printf("t: %v, tNext: %v,  dtReal: %v, tReal: %v", tm.T(), tNext, dtReal, tReal)
 					tm.Step()
 				} else {
 					break
@@ -199,10 +307,21 @@ main:
 }
 
 // Step processes the next event.
+// Events that were cancelled via [Timer.Stop] are discarded without
+// advancing the simulation time.
 func (tm *TimeMachine) Step() {
-	ev := tm.eventQueue.next()
-	tm.t = ev.t
-	ev.f()
+	for {
+		ev := tm.eventQueue.next()
+		if ev == nil {
+			return
+		}
+		if !ev.markFired() {
+			continue //the event was cancelled before it could fire.
+		}
+		tm.setT(ev.t)
+		ev.f()
+		break
+	}
 	//check for new events
 	for {
 		select {
@@ -212,5 +331,4 @@ func (tm *TimeMachine) Step() {
 			return
 		}
 	}
-
 }
@@ -102,6 +102,53 @@ func TestTimeMachinePauseResume(t *testing.T) {
 	}
 }
 
+func TestTimeMachineScheduleCancel(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 2)
+	tm.Start()
+	executed := false
+	timer := tm.Schedule(100, func() {
+		executed = true
+	})
+	if !timer.Stop() {
+		t.Error("Expected Stop to return true for a pending timer")
+	}
+	if timer.Stop() {
+		t.Error("Expected Stop to return false for an already stopped timer")
+	}
+	time.Sleep(200 * time.Millisecond) // wait for the event to have been skipped
+	if executed {
+		t.Errorf("Expected cancelled event NOT to be executed")
+	}
+}
+
+func TestTimeMachineCancelManyTimersFromCallback(t *testing.T) {
+	// 20 timers are cancelled synchronously from inside a single callback,
+	// mirroring a cancel-other-pending-timeouts-from-an-ack-handler
+	// workload. A cancels channel bounded by the (small) eventChanSize used
+	// to deadlock here: run() is the only goroutine that ever reads it, and
+	// it's the very goroutine blocked sending to it.
+	const numTimers = 20
+	tm := NewTimeMachine(1.0, numTimers+1, 1)
+	var timers []*Timer
+	for i := 0; i < numTimers; i++ {
+		timers = append(timers, tm.Schedule(float64(100+i), func() {}))
+	}
+	done := make(chan struct{})
+	tm.Schedule(0, func() {
+		for _, timer := range timers {
+			timer.Stop()
+		}
+		close(done)
+	})
+	tm.Start()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected cancelling many timers from within a callback not to deadlock")
+	}
+	tm.Stop()
+}
+
 func TestTimeMachineStop(t *testing.T) {
 	tm := NewTimeMachine(1.0, 10, 2)
 	tm.Start()
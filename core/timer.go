@@ -0,0 +1,55 @@
+package core
+
+import "log"
+
+// Timer represents a single pending event scheduled via [TimeMachine.Schedule]
+// or a [Clock]'s AfterFunc. It mirrors the semantics of [time.Timer],
+// allowing the event to be cancelled or rescheduled before it fires.
+type Timer struct {
+	stop  func() bool
+	reset func(d float64) bool
+}
+
+// Stop cancels the timer, preventing its function from running.
+//
+// It returns true if the call stops the timer, false if the timer has
+// already fired or was already stopped.
+func (t *Timer) Stop() bool {
+	return t.stop()
+}
+
+// Reset cancels the previous firing, if still pending, and arms the timer to
+// run again in d ms.
+//
+// It returns true if the previous firing was still pending before being
+// rescheduled. Resetting a timer that already fired simply re-arms it.
+func (t *Timer) Reset(d float64) bool {
+	return t.reset(d)
+}
+
+// newEventTimer creates a Timer backed by an Event scheduled on tm.
+func newEventTimer(tm *TimeMachine, event *Event, f func()) *Timer {
+	t := &Timer{}
+	t.stop = func() bool {
+		wasPending := event.cancel()
+		if wasPending {
+			tm.eventQueue.remove(event)
+		}
+		return wasPending
+	}
+	t.reset = func(d float64) bool {
+		wasPending := event.cancel()
+		if wasPending {
+			tm.eventQueue.remove(event)
+		}
+		if d < 0 {
+			log.Printf("dt = %v < 0. Will be reset to 0.0", d)
+			d = 0.0
+		}
+		event = newEvent(tm.T()+d, f)
+		event.seq = tm.seq.Add(1)
+		tm.events <- event
+		return wasPending
+	}
+	return t
+}
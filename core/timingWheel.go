@@ -0,0 +1,309 @@
+package core
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// wheelSlots is the number of buckets per level of a TimingWheelQueue.
+// wheelLevels is the number of such levels before events overflow into the
+// overflow heap.
+const (
+	wheelSlots  = 256
+	wheelLevels = 4
+)
+
+// TimingWheelQueue is an alternative to EventQueue backed by a hierarchical
+// timing wheel: several rings of wheelSlots buckets each, with level L
+// covering wheelSlots^(L+1) ticks of tickDuration ms. Scheduling and
+// cancellation are amortized O(1), trading away EventQueue's O(log n) but
+// exact ordering for coarser time resolution (events due within the same
+// tickDuration are not kept in strict relative order). This fits
+// simulations holding hundreds of thousands of pending events, where
+// EventQueue's O(log n) heap operations start to dominate runtime.
+//
+// Events scheduled further out than the outermost level's horizon
+// (wheelSlots^wheelLevels ticks) are held in an overflow heap and
+// re-cascaded into the wheel as its base time advances.
+//
+// A TimingWheelQueue must be created with [NewTimingWheelQueue] and is used
+// via [NewTimeMachineWithQueue]; it is safe for concurrent use by multiple
+// goroutines.
+type TimingWheelQueue struct {
+	mu           sync.Mutex
+	tickDuration float64 // ms represented by one level-0 slot.
+	epoch        float64 // simulation time that tick 0 represents.
+	jiffies      int64   // current absolute tick; advances as events are consumed.
+	levels       [wheelLevels][wheelSlots][]*Event
+	overflow     eventHeap
+	size         int
+}
+
+// NewTimingWheelQueue creates a TimingWheelQueue whose level-0 buckets each
+// span tickDuration ms of simulation time. tickDuration should be chosen no
+// finer than the smallest time difference between events that matters to
+// the simulation, since events within the same level-0 bucket are not
+// ordered relative to one another beyond (t, schedule order).
+func NewTimingWheelQueue(tickDuration float64) *TimingWheelQueue {
+	w := &TimingWheelQueue{tickDuration: tickDuration}
+	heap.Init(&w.overflow)
+	return w
+}
+
+// ticksFor converts a simulation time to an absolute tick index.
+func (w *TimingWheelQueue) ticksFor(t float64) int64 {
+	delta := t - w.epoch
+	if delta < 0 {
+		delta = 0
+	}
+	return int64(delta / w.tickDuration)
+}
+
+// levelFor returns the wheel level whose horizon covers idx ticks from the
+// current jiffies, or ok=false if idx is beyond the outermost level and the
+// event belongs in the overflow heap.
+func levelFor(idx int64) (level int, ok bool) {
+	span := int64(wheelSlots)
+	for level = 0; level < wheelLevels; level++ {
+		if idx < span {
+			return level, true
+		}
+		span *= wheelSlots
+	}
+	return 0, false
+}
+
+// levelUnit returns the number of ticks represented by one slot at level.
+func levelUnit(level int) int64 {
+	unit := int64(1)
+	for i := 0; i < level; i++ {
+		unit *= wheelSlots
+	}
+	return unit
+}
+
+// fileLocked places event into the wheel or the overflow heap according to
+// its due tick. Callers hold w.mu and are responsible for w.size bookkeeping.
+func (w *TimingWheelQueue) fileLocked(event *Event) {
+	ticks := w.ticksFor(event.t)
+	if ticks < w.jiffies {
+		ticks = w.jiffies
+	}
+	level, ok := levelFor(ticks - w.jiffies)
+	if !ok {
+		heap.Push(&w.overflow, event)
+		return
+	}
+	slot := int((ticks / levelUnit(level)) % wheelSlots)
+	w.levels[level][slot] = append(w.levels[level][slot], event)
+}
+
+// add adds a new event to the queue in O(1). An event that was cancelled
+// before it could be added is silently dropped.
+func (w *TimingWheelQueue) add(event *Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if event.state.Load() == int32(eventCancelled) {
+		return
+	}
+	w.fileLocked(event)
+	w.size++
+}
+
+// remove is a no-op: a cancelled event is left in its bucket, still returned
+// by next(), and skipped by the caller once popped (s. Event.markFired), since locating it
+// within its bucket ahead of time would cost the O(1) guarantee remove is
+// meant to preserve.
+func (w *TimingWheelQueue) remove(event *Event) {}
+
+// earliest returns the index of the event with the smallest (t, seq) within
+// bucket.
+func earliest(bucket []*Event) int {
+	best := 0
+	for i := 1; i < len(bucket); i++ {
+		if less(bucket[i], bucket[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func less(a, b *Event) bool {
+	if a.t != b.t {
+		return a.t < b.t
+	}
+	return a.seq < b.seq
+}
+
+// advanceTo jumps the wheel's base time directly to target, cascading the
+// next higher level's current bucket down if target lands on a level-0
+// round boundary, exactly as stepping there one tick at a time would have.
+// Callers must only pass a target returned by nextCascadeTick, so every
+// tick skipped over is known to hold nothing relevant.
+func (w *TimingWheelQueue) advanceTo(target int64) {
+	if target <= w.jiffies {
+		return
+	}
+	w.jiffies = target
+	if w.jiffies%wheelSlots == 0 {
+		w.cascade(1)
+	}
+}
+
+// nextCascadeTick returns the soonest tick after the current one at which a
+// non-empty bucket would cascade or an overflow event would fall within the
+// outermost level's horizon, or ok=false if the wheel and overflow are both
+// fully empty. Each level's wheelSlots buckets are scanned once, so this
+// costs O(wheelSlots*wheelLevels) regardless of how far off that tick is,
+// which is what makes advancing past a long gap between events amortized
+// O(1) instead of O(gap).
+func (w *TimingWheelQueue) nextCascadeTick() (tick int64, ok bool) {
+	consider := func(t int64) {
+		if !ok || t < tick {
+			tick, ok = t, true
+		}
+	}
+	for level := 0; level < wheelLevels; level++ {
+		cur := int((w.jiffies / levelUnit(level)) % wheelSlots)
+		for i := 0; i < wheelSlots; i++ {
+			slot := (cur + i) % wheelSlots
+			if len(w.levels[level][slot]) == 0 {
+				continue
+			}
+			consider(boundaryTick(level, slot, w.jiffies))
+		}
+	}
+	if w.overflow.Len() > 0 {
+		if t, ok2 := w.overflow.nextT(); ok2 {
+			ticks := w.ticksFor(t)
+			if ticks < w.jiffies {
+				ticks = w.jiffies
+			}
+			consider(ticks)
+		}
+	}
+	return tick, ok
+}
+
+// boundaryTick returns the smallest tick not before after at which the
+// bucket at (level, slot) cascades, i.e. the next time the wheel's jiffies
+// counter reaches slot*levelUnit(level) modulo that level's full span.
+func boundaryTick(level, slot int, after int64) int64 {
+	unit := levelUnit(level)
+	span := unit * wheelSlots
+	target := (after/span)*span + int64(slot)*unit
+	if target < after {
+		target += span
+	}
+	return target
+}
+
+func (w *TimingWheelQueue) cascade(level int) {
+	if level >= wheelLevels {
+		w.drainOverflowLocked()
+		return
+	}
+	slot := int((w.jiffies / levelUnit(level)) % wheelSlots)
+	bucket := w.levels[level][slot]
+	w.levels[level][slot] = nil
+	for _, ev := range bucket {
+		w.fileLocked(ev)
+	}
+	if slot == 0 {
+		w.cascade(level + 1)
+	}
+}
+
+// drainOverflowLocked re-files overflow events that now fall within the
+// outermost level's horizon back into the wheel.
+func (w *TimingWheelQueue) drainOverflowLocked() {
+	var remaining eventHeap
+	heap.Init(&remaining)
+	for w.overflow.Len() > 0 {
+		ev := heap.Pop(&w.overflow).(*Event)
+		ticks := w.ticksFor(ev.t)
+		if ticks < w.jiffies {
+			ticks = w.jiffies
+		}
+		if _, ok := levelFor(ticks - w.jiffies); ok {
+			w.fileLocked(ev)
+		} else {
+			heap.Push(&remaining, ev)
+		}
+	}
+	w.overflow = remaining
+}
+
+// nextT returns the time of the next due event in ms, advancing the wheel's
+// internal cursor as needed to locate it. This only reshuffles events
+// between buckets and never loses or fires one, so it is safe to call
+// without committing to consuming the event (s. next).
+// If there are no more events then ok is false.
+func (w *TimingWheelQueue) nextT() (t float64, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for {
+		if w.size == 0 {
+			return 0, false
+		}
+		if bucket := w.levels[0][w.jiffies%wheelSlots]; len(bucket) > 0 {
+			return bucket[earliest(bucket)].t, true
+		}
+		if w.overflow.Len() > 0 {
+			if t, ok := w.overflow.nextT(); ok && w.ticksFor(t) <= w.jiffies {
+				return t, true
+			}
+		}
+		target, ok := w.nextCascadeTick()
+		if !ok {
+			return 0, false
+		}
+		w.advanceTo(target)
+	}
+}
+
+// next retrieves and removes the next due event from the queue, advancing
+// the wheel's internal cursor as needed to locate it.
+func (w *TimingWheelQueue) next() *Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for {
+		if w.size == 0 {
+			return nil
+		}
+		slot := w.jiffies % wheelSlots
+		if bucket := w.levels[0][slot]; len(bucket) > 0 {
+			i := earliest(bucket)
+			ev := bucket[i]
+			w.levels[0][slot] = append(bucket[:i], bucket[i+1:]...)
+			w.size--
+			return ev
+		}
+		if w.overflow.Len() > 0 {
+			if t, ok := w.overflow.nextT(); ok && w.ticksFor(t) <= w.jiffies {
+				ev := heap.Pop(&w.overflow).(*Event)
+				w.size--
+				return ev
+			}
+		}
+		target, ok := w.nextCascadeTick()
+		if !ok {
+			return nil
+		}
+		w.advanceTo(target)
+	}
+}
+
+// all returns every event currently in the queue, in no particular order.
+func (w *TimingWheelQueue) all() []*Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]*Event, 0, w.size)
+	for _, level := range w.levels {
+		for _, bucket := range level {
+			out = append(out, bucket...)
+		}
+	}
+	out = append(out, w.overflow...)
+	return out
+}
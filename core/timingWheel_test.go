@@ -0,0 +1,99 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingWheelQueueOrdering(t *testing.T) {
+	w := NewTimingWheelQueue(1.0)
+	var order []int
+	times := []float64{500, 10, 70000, 1, 2000000, 30000000, 5000000000} // spans all levels and the overflow heap.
+	for i, tt := range times {
+		ev := newEvent(tt, nil)
+		ev.seq = uint64(i)
+		w.add(ev)
+	}
+	var lastT float64 = -1
+	for {
+		tNext, ok := w.nextT()
+		if !ok {
+			break
+		}
+		ev := w.next()
+		if ev.t != tNext {
+			t.Errorf("expected next() time %v to equal nextT() %v", ev.t, tNext)
+		}
+		if ev.t < lastT {
+			t.Errorf("expected next event time %v to be >= last event time %v", ev.t, lastT)
+		}
+		lastT = ev.t
+		order = append(order, int(ev.seq))
+	}
+	if len(order) != len(times) {
+		t.Fatalf("expected %d events, got %d", len(times), len(order))
+	}
+}
+
+func TestTimingWheelQueueCancellationIsSkipped(t *testing.T) {
+	w := NewTimingWheelQueue(1.0)
+	a := newEvent(10, nil)
+	b := newEvent(20, nil)
+	w.add(a)
+	w.add(b)
+	if !a.cancel() {
+		t.Fatal("expected a to be cancellable")
+	}
+	var fired []*Event
+	for {
+		ev := w.next()
+		if ev == nil {
+			break
+		}
+		if ev.markFired() {
+			fired = append(fired, ev)
+		}
+	}
+	if len(fired) != 1 || fired[0] != b {
+		t.Errorf("expected only b to fire, got %v", fired)
+	}
+}
+
+func TestTimingWheelQueueAll(t *testing.T) {
+	w := NewTimingWheelQueue(1.0)
+	w.add(newEvent(5, nil))
+	w.add(newEvent(5000000000, nil)) // beyond the outermost level, lands in overflow.
+	if got := len(w.all()); got != 2 {
+		t.Errorf("expected all() to report 2 pending events, got %d", got)
+	}
+}
+
+func TestTimingWheelQueueAdvancesSparseScheduleQuickly(t *testing.T) {
+	w := NewTimingWheelQueue(1.0)
+	w.add(newEvent(0, nil))
+	w.add(newEvent(2000000, nil)) // far enough apart that ticking one-by-one would be slow.
+	if ev := w.next(); ev == nil || ev.t != 0 {
+		t.Fatalf("expected first next() to return the event at t=0, got %v", ev)
+	}
+	start := time.Now()
+	tNext, ok := w.nextT()
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected nextT() across a 2,000,000-tick gap to be fast, took %v", elapsed)
+	}
+	if !ok || tNext != 2000000 {
+		t.Errorf("expected nextT() to report the event at t=2000000, got %v, %v", tNext, ok)
+	}
+}
+
+func TestNewTimeMachineWithQueue(t *testing.T) {
+	tm := NewTimeMachineWithQueue(1.0, 10, 1, NewTimingWheelQueue(1.0))
+	fired := make(chan struct{}, 1)
+	tm.Schedule(10, func() { fired <- struct{}{} })
+	tm.Start()
+	defer tm.Stop()
+	select {
+	case <-fired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the scheduled event to fire")
+	}
+}
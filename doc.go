@@ -11,5 +11,18 @@ We distinguish two different notions of time:
 The TM - once started - executes the scheduled function in a strictly time ordered manner.
 
 Alternatively and for fastest processing the client may call [TimeMachine.Step] in a loop, which completely ignores the real time.
+
+# Concurrency
+
+[TimeMachine.Schedule] is safe to call from any number of goroutines
+concurrently and never blocks, even while the simulation is running; pending
+events are queued in an internal inbox and drained into the event queue by
+the TimeMachine's own goroutine. [TimeMachine.T], [TimeMachine.State] and
+[TimeMachine.Speed] are likewise safe to read concurrently with a running
+simulation. The lifecycle methods ([TimeMachine.Start], [TimeMachine.Pause],
+[TimeMachine.Resume], [TimeMachine.Stop], [TimeMachine.SetSpeed]) are not
+meant to be called concurrently with each other. [TimeMachine.AttachObserver]
+and [TimeMachine.Step] must not be called concurrently with a running
+simulation.
 */
 package sima
@@ -0,0 +1,36 @@
+package sima
+
+import (
+	"sync"
+
+	"github.com/jcderner/sima/internal/core"
+)
+
+// eventInbox is an unbounded, concurrency-safe mailbox for events pending
+// insertion into the TimeMachine's eventQueue. It lets any number of
+// goroutines call [TimeMachine.Schedule] concurrently without ever blocking,
+// unlike a fixed-size channel that fills up.
+type eventInbox struct {
+	mu    sync.Mutex
+	items []*core.Event
+}
+
+func newEventInbox(capacityHint int) *eventInbox {
+	return &eventInbox{items: make([]*core.Event, 0, capacityHint)}
+}
+
+// push adds ev to the inbox. It never blocks.
+func (b *eventInbox) push(ev *core.Event) {
+	b.mu.Lock()
+	b.items = append(b.items, ev)
+	b.mu.Unlock()
+}
+
+// drain removes and returns all events currently pending in the inbox.
+func (b *eventInbox) drain() []*core.Event {
+	b.mu.Lock()
+	items := b.items
+	b.items = nil
+	b.mu.Unlock()
+	return items
+}
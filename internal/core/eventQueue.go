@@ -2,12 +2,41 @@ package core
 
 import (
 	"container/heap"
+	"sync/atomic"
+)
+
+// eventState tracks the lifecycle of an Event for cancellation purposes.
+type eventState int32
+
+const (
+	eventPending eventState = iota
+	eventCancelled
+	eventFired
 )
 
 // Event represents a single Event in the simulation
 type Event struct {
-	T float64
-	F func()
+	T     float64
+	F     func()
+	state atomic.Int32
+}
+
+// Cancel marks the event as cancelled so it will be skipped instead of fired.
+// It returns true if the event was pending and is now cancelled, false if it
+// had already fired or was already cancelled.
+func (e *Event) Cancel() bool {
+	return e.state.CompareAndSwap(int32(eventPending), int32(eventCancelled))
+}
+
+// Cancelled reports whether the event has been cancelled.
+func (e *Event) Cancelled() bool {
+	return e.state.Load() == int32(eventCancelled)
+}
+
+// MarkFired transitions the event to the fired state if it is still pending.
+// It returns false if the event was cancelled before it could fire.
+func (e *Event) MarkFired() bool {
+	return e.state.CompareAndSwap(int32(eventPending), int32(eventFired))
 }
 
 // EventQueue implements a priority queue for Events.
@@ -0,0 +1,97 @@
+package sima
+
+import "time"
+
+// Observer receives notifications about a TimeMachine's lifecycle and event
+// processing. It is the extension point for metrics and tracing; see
+// [PrometheusObserver] and [JSONLTraceObserver] for ready-made
+// implementations.
+//
+// All methods are called synchronously from the TimeMachine's goroutines, so
+// implementations must not block or call back into the TimeMachine.
+type Observer interface {
+	// OnSchedule is called when Schedule or [Timer.Reset] is used to schedule
+	// an event dt ms after the current simulation time t.
+	OnSchedule(t, dt float64)
+	// OnFire is called after an event at simulation time t has run, with dur
+	// the real time its handler took to execute.
+	OnFire(t float64, dur time.Duration)
+	// OnCancel is called when a pending event scheduled for simulation time
+	// t is cancelled via [Timer.Stop] or [Timer.Reset] before it fires.
+	OnCancel(t float64)
+	// OnStateChange is called whenever the TimeMachine transitions from old
+	// to new.
+	OnStateChange(old, new TMState)
+	// OnDrift is called once per run cycle while the TimeMachine is RUNNING,
+	// with the current simulation time and the real time elapsed since the
+	// TimeMachine started, not counting time spent paused (both in ms),
+	// letting an Observer track how far the simulation has drifted from real
+	// time.
+	OnDrift(simT, realT float64)
+}
+
+// AttachObserver registers o to receive notifications about this
+// TimeMachine's lifecycle and event processing.
+//
+// AttachObserver is not safe to call concurrently with Schedule, Step, or a
+// running simulation.
+func (tm *TimeMachine) AttachObserver(o Observer) {
+	tm.observers = append(tm.observers, o)
+}
+
+// notifySchedule notifies all attached observers that an event was scheduled.
+func (tm *TimeMachine) notifySchedule(t, dt float64) {
+	for _, o := range tm.observers {
+		o.OnSchedule(t, dt)
+	}
+}
+
+// notifyFire notifies all attached observers that an event has fired.
+func (tm *TimeMachine) notifyFire(t float64, dur time.Duration) {
+	for _, o := range tm.observers {
+		o.OnFire(t, dur)
+	}
+}
+
+// notifyCancel notifies all attached observers that a pending event was cancelled.
+func (tm *TimeMachine) notifyCancel(t float64) {
+	for _, o := range tm.observers {
+		o.OnCancel(t)
+	}
+}
+
+// notifyDrift notifies all attached observers of the current simulation-vs-real drift.
+func (tm *TimeMachine) notifyDrift(simT, realT float64) {
+	for _, o := range tm.observers {
+		o.OnDrift(simT, realT)
+	}
+}
+
+// setState transitions the TimeMachine to s and notifies all attached
+// observers of the transition. It is only safe to call from the run
+// goroutine.
+func (tm *TimeMachine) setState(s TMState) {
+	tm.mu.Lock()
+	old := tm.state
+	tm.state = s
+	tm.mu.Unlock()
+	for _, o := range tm.observers {
+		o.OnStateChange(old, s)
+	}
+}
+
+// setSpeed updates the simulation speed. It is only safe to call from the
+// run goroutine.
+func (tm *TimeMachine) setSpeed(speed float64) {
+	tm.mu.Lock()
+	tm.speed = speed
+	tm.mu.Unlock()
+}
+
+// setT advances the simulation time to t. It is called by Step, which runs
+// on the run goroutine or, while no simulation is running, on the caller's.
+func (tm *TimeMachine) setT(t float64) {
+	tm.mu.Lock()
+	tm.t = t
+	tm.mu.Unlock()
+}
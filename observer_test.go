@@ -0,0 +1,102 @@
+package sima
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type recordingObserver struct {
+	scheduled   int
+	fired       int
+	cancelled   int
+	transitions []string
+	drifts      int
+}
+
+func (o *recordingObserver) OnSchedule(t, dt float64)            { o.scheduled++ }
+func (o *recordingObserver) OnFire(t float64, dur time.Duration) { o.fired++ }
+func (o *recordingObserver) OnCancel(t float64)                  { o.cancelled++ }
+func (o *recordingObserver) OnStateChange(old, new TMState) {
+	o.transitions = append(o.transitions, old.String()+"->"+new.String())
+}
+func (o *recordingObserver) OnDrift(simT, realT float64) { o.drifts++ }
+
+func TestTimeMachineAttachObserver(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	obs := &recordingObserver{}
+	tm.AttachObserver(obs)
+	tm.Schedule(10, func() {})
+	tm.Start()
+	time.Sleep(100 * time.Millisecond)
+	tm.Stop()
+	if obs.scheduled != 1 {
+		t.Errorf("Expected 1 OnSchedule notification, got %v", obs.scheduled)
+	}
+	if obs.fired != 1 {
+		t.Errorf("Expected 1 OnFire notification, got %v", obs.fired)
+	}
+	if len(obs.transitions) == 0 {
+		t.Error("Expected at least one OnStateChange notification")
+	}
+}
+
+func TestTimeMachineAttachObserverOnCancel(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	obs := &recordingObserver{}
+	tm.AttachObserver(obs)
+	timer := tm.Schedule(100, func() {})
+	if !timer.Stop() {
+		t.Fatal("expected Stop to return true for a pending timer")
+	}
+	if obs.cancelled != 1 {
+		t.Errorf("Expected 1 OnCancel notification, got %v", obs.cancelled)
+	}
+}
+
+func TestJSONLTraceObserver(t *testing.T) {
+	var buf bytes.Buffer
+	obs := NewJSONLTraceObserver(&buf)
+	obs.OnSchedule(0, 10)
+	obs.OnFire(10, 5*time.Millisecond)
+	obs.OnCancel(20)
+	obs.OnStateChange(IDLE, RUNNING)
+	obs.OnDrift(10, 12)
+
+	scanner := bufio.NewScanner(&buf)
+	var records []traceRecord
+	for scanner.Scan() {
+		var r traceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("could not unmarshal trace record: %v", err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 trace records, got %v", len(records))
+	}
+	if records[0].Type != "schedule" || records[2].Type != "cancel" || records[3].Type != "stateChange" {
+		t.Errorf("Unexpected record types: %+v", records)
+	}
+}
+
+func TestPrometheusObserver(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	obs := NewPrometheusObserver(reg)
+	obs.OnSchedule(0, 10)
+	obs.OnFire(10, 5*time.Millisecond)
+	obs.OnCancel(20)
+	obs.OnDrift(10, 12)
+
+	metrics, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("could not gather metrics: %v", err)
+	}
+	if len(metrics) != 4 {
+		t.Errorf("Expected 4 registered metric families, got %v", len(metrics))
+	}
+}
@@ -0,0 +1,33 @@
+package sima
+
+// ScheduleAt schedules f to run at the absolute simulation time t rather
+// than at an offset from the current simulation time.
+//
+// It returns a [Timer] handle that can be used to cancel or reschedule the
+// event before it fires.
+func (tm *TimeMachine) ScheduleAt(t float64, f func()) *Timer {
+	return tm.Schedule(t-tm.T(), f)
+}
+
+// SchedulePeriodic schedules f to run every period ms of simulation time,
+// starting dt ms after the current simulation time. Each re-scheduling is
+// anchored to simulation time rather than real time, so pausing the
+// TimeMachine or changing its speed does not drift the cadence.
+//
+// The returned Timer's Stop cancels the pending firing and prevents any
+// further ones.
+func (tm *TimeMachine) SchedulePeriodic(dt, period float64, f func()) *Timer {
+	t := &Timer{tm: tm, f: f, period: period}
+	t.event = tm.Schedule(dt, t.firePeriodic).event
+	return t
+}
+
+// firePeriodic runs the timer's function and, unless the timer has been
+// stopped in the meantime, re-arms it period ms later.
+func (t *Timer) firePeriodic() {
+	t.f()
+	if t.stopped.Load() {
+		return
+	}
+	t.event = t.tm.Schedule(t.period, t.firePeriodic).event
+}
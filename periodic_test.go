@@ -0,0 +1,40 @@
+package sima
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeMachineScheduleAt(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 2)
+	tm.Start()
+	executed := false
+	tm.ScheduleAt(100, func() {
+		executed = true
+	})
+	time.Sleep(200 * time.Millisecond) // wait for the event to be processed
+	if !executed {
+		t.Errorf("Expected the event scheduled at t=100 to be executed")
+	}
+	tm.Stop()
+}
+
+func TestTimeMachineSchedulePeriodic(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	tm.Start()
+	count := 0
+	timer := tm.SchedulePeriodic(10, 10, func() {
+		count++
+	})
+	time.Sleep(100 * time.Millisecond) // let several periods elapse
+	timer.Stop()
+	countAfterStop := count
+	time.Sleep(50 * time.Millisecond)
+	if count < 3 {
+		t.Errorf("Expected the periodic event to have fired several times, got %v", count)
+	}
+	if count != countAfterStop {
+		t.Errorf("Expected no more firings after Stop, got %v additional", count-countAfterStop)
+	}
+	tm.Stop()
+}
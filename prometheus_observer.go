@@ -0,0 +1,68 @@
+package sima
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusObserver is an [Observer] that exposes a histogram of event
+// handler durations, a gauge of the pending event queue depth, a counter of
+// events fired, and a gauge of simulation-vs-real drift as Prometheus
+// metrics.
+type PrometheusObserver struct {
+	handlerDuration prometheus.Histogram
+	eventsFired     prometheus.Counter
+	queueDepth      prometheus.Gauge
+	drift           prometheus.Gauge
+}
+
+// NewPrometheusObserver creates a PrometheusObserver and registers its
+// metrics with reg.
+func NewPrometheusObserver(reg prometheus.Registerer) *PrometheusObserver {
+	p := &PrometheusObserver{
+		handlerDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "sima_event_handler_duration_seconds",
+			Help: "Duration of TimeMachine event handlers.",
+		}),
+		eventsFired: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sima_events_fired_total",
+			Help: "Total number of events fired by the TimeMachine.",
+		}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sima_event_queue_depth",
+			Help: "Number of events currently pending in the TimeMachine's event queue.",
+		}),
+		drift: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sima_drift_milliseconds",
+			Help: "Difference between simulation time and real time, in ms.",
+		}),
+	}
+	reg.MustRegister(p.handlerDuration, p.eventsFired, p.queueDepth, p.drift)
+	return p
+}
+
+// OnSchedule implements [Observer].
+func (p *PrometheusObserver) OnSchedule(t, dt float64) {
+	p.queueDepth.Inc()
+}
+
+// OnFire implements [Observer].
+func (p *PrometheusObserver) OnFire(t float64, dur time.Duration) {
+	p.handlerDuration.Observe(dur.Seconds())
+	p.eventsFired.Inc()
+	p.queueDepth.Dec()
+}
+
+// OnCancel implements [Observer].
+func (p *PrometheusObserver) OnCancel(t float64) {
+	p.queueDepth.Dec()
+}
+
+// OnStateChange implements [Observer].
+func (p *PrometheusObserver) OnStateChange(old, new TMState) {}
+
+// OnDrift implements [Observer].
+func (p *PrometheusObserver) OnDrift(simT, realT float64) {
+	p.drift.Set(simT - realT)
+}
@@ -1,14 +1,28 @@
 package sima
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jcderner/sima/internal/core"
 )
 
+// Errors returned by the TimeMachine's lifecycle methods (Start, Pause,
+// Resume, Stop, SetSpeed) to let callers distinguish "the TimeMachine was in
+// the wrong state" from "the argument was invalid" using [errors.Is].
+var (
+	ErrAlreadyStarted = errors.New("sima: TimeMachine is already started")
+	ErrNotRunning     = errors.New("sima: TimeMachine is not running")
+	ErrNotPaused      = errors.New("sima: TimeMachine is not paused")
+	ErrAlreadyStopped = errors.New("sima: TimeMachine is already stopped")
+	ErrInvalidSpeed   = errors.New("sima: speed must not be smaller than 0.01")
+)
+
 type TMState int
 
 const (
@@ -38,42 +52,67 @@ func (s TMState) String() string {
 // When the TM is started, the simulation time proceeds with a certain speed (s. [TimeMachin.Speed] and [TimeMachin.SetSpeed])
 // compared to the real time.
 // Alternatively and for fastest processing the client may call [TimeMachine.Step] in a loop, which completely ignores the real time.
+//
+// Concurrency: [TimeMachine.Schedule] may be called from any number of
+// goroutines concurrently and never blocks. [TimeMachine.T], [TimeMachine.State]
+// and [TimeMachine.Speed] may likewise be read concurrently with a running
+// simulation. The lifecycle methods (Start, Pause, Resume, Stop, SetSpeed)
+// are not meant to be called concurrently with each other.
 type TimeMachine struct {
+	clock      Clock
 	eventQueue *core.EventQueue
-	t          float64 //the actual simulation time in ms.
-	cycleTime  int     //every cycleTime [ms] the time machine will check for new events.
+	mu         sync.RWMutex //guards t, state and speed below against the run goroutine.
+	t          float64      //the actual simulation time in ms.
+	cycleTime  int          //every cycleTime [ms] the time machine will check for new events.
 	state      TMState
 	speed      float64 //ratio between simulation and real time.
 	cmds       chan string
-	events     chan *core.Event
-	done       chan bool
+	events     *eventInbox
+	done       chan error
+	observers  []Observer
 }
 
-// NewTimeMachine creates a reference to a new TimeMachine.
+// NewTimeMachine creates a reference to a new TimeMachine driven by the
+// [SystemClock], i.e. by the real wall clock.
 //
 //   - The speed is the ratio between simulation and real time. If it is smaller than 0.01 it will be reset to 0.01.
-//   - The eventChanSize is the capacity of the events channel.
-//     It should be at least the number of events that are expected to be scheduled initially or in one cycle.
+//   - The eventChanSize is a capacity hint for the internal event inbox.
+//     It should be at least the number of events that are expected to be scheduled initially or in one cycle,
+//     but Schedule never blocks even if more events than that are pending.
 //   - The cycleTime is the real time in ms between two checks for new commands and events.
 func NewTimeMachine(speed float64, eventChanSize int, cycleTime int) *TimeMachine {
+	return NewTimeMachineWithClock(SystemClock{}, speed, eventChanSize, cycleTime)
+}
+
+// NewTimeMachineWithClock creates a reference to a new TimeMachine driven by
+// clock instead of the real wall clock. This lets tests use a
+// [SimulatedClock] to drive the TimeMachine deterministically, and lets
+// callers compose sima with their own simulated environments.
+//
+// The remaining parameters are as in [NewTimeMachine].
+func NewTimeMachineWithClock(clock Clock, speed float64, eventChanSize int, cycleTime int) *TimeMachine {
 	if speed < 0.01 {
 		log.Printf("Speed = %v must not be smaller than 0.01. It will be reset to 0.01.", speed)
 		speed = 0.01
 	}
 	return &TimeMachine{
+		clock:      clock,
 		eventQueue: core.NewEventQueue(),
 		t:          0,
 		cycleTime:  cycleTime,
 		state:      IDLE,
 		speed:      speed,
 		cmds:       make(chan string),
-		done:       make(chan bool), //channel to signal that a command has been executed.
-		events:     make(chan *core.Event, eventChanSize),
+		done:       make(chan error), //channel to signal that a command has been executed.
+		events:     newEventInbox(eventChanSize),
 	}
 }
 
 // T returns the current simulation time of the time machine.
+// It is safe to call concurrently with a running simulation.
 func (tm *TimeMachine) T() float64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.t
 }
 
@@ -82,206 +121,208 @@ func (tm *TimeMachine) T() float64 {
 //   - RUNNING: The TM is running by executing in (speed * real_time) one scheduled function after another.
 //   - PAUSED:  The TM is paused. A paused TM can resume.
 //   - STOPPED: The TM is stopped. A stopped TM is stopped forever.
+//
+// It is safe to call concurrently with a running simulation.
 func (tm *TimeMachine) State() TMState {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.state
 }
 
 // Speed returns the speed of the simulation; i.e.: the ratio between simulation time and real time duration.
+// It is safe to call concurrently with a running simulation.
 func (tm *TimeMachine) Speed() float64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.speed
 }
 
 // Schedule schedules a function f to be executed dt ms after the current simulation time.
 // f needs to be a parameterless function (or method or closure) without return value
 // If dt is negative, it will be reset to 0.0.
-func (tm *TimeMachine) Schedule(dt float64, f func()) {
+//
+// Schedule is safe to call concurrently from any number of goroutines and
+// never blocks.
+//
+// It returns a [Timer] handle that can be used to cancel or reschedule the event
+// before it fires.
+func (tm *TimeMachine) Schedule(dt float64, f func()) *Timer {
 	if dt < 0 {
 		log.Printf("dt = %v < 0. Will be reset to 0.0", dt)
 		dt = 0.0
 	}
-	tm.events <- (&core.Event{T: tm.t + dt, F: f})
+	t := tm.T()
+	tm.notifySchedule(t, dt)
+	ev := &core.Event{T: t + dt, F: f}
+	tm.events.push(ev)
+	return &Timer{tm: tm, event: ev, f: f}
 }
 
 // Start starts the simulation in a go routine.
 //
-// It returns true when the start was successful and false otherwise.
-func (tm *TimeMachine) Start() (success bool) {
-	if tm.state != IDLE {
-		log.Printf("TimeMachine is not in IDLE state. Ignoring Start command.")
-		return
+// It returns ErrAlreadyStarted if the TimeMachine is not in the IDLE state.
+func (tm *TimeMachine) Start() error {
+	if tm.State() != IDLE {
+		return ErrAlreadyStarted
 	}
 	go tm.run()
-	done := <-tm.done
-	if done {
-		return true
-	} else {
-		return false
-	}
+	return <-tm.done
 }
 
 // Pause pauses the simulation.
 // It remembers the current real time to continue smoothly after a resume.
 //
-// It returns true when the TM has paused and false otherwise.
-func (tm *TimeMachine) Pause() (success bool) {
-	if tm.state != RUNNING {
-		log.Printf("TimeMachine is not in RUNNING state. Ignoring Pause command.")
-		return
+// It returns ErrNotRunning if the TimeMachine is not in the RUNNING state.
+func (tm *TimeMachine) Pause() error {
+	if tm.State() != RUNNING {
+		return ErrNotRunning
 	}
 	tm.cmds <- "PAUSE"
-	done := <-tm.done
-	if done {
-		return true
-	} else {
-		return false
-	}
+	return <-tm.done
 }
 
 // Resume resumes the simulation.
 // It calculates an offset from the last pause (and previous offsets) to continue smoothly.
 //
-// It returns true when the TM has resumed running and false otherwise.
-func (tm *TimeMachine) Resume() (success bool) {
-	if tm.state != PAUSED {
-		log.Printf("TimeMachine is not in PAUSED state. Ignoring Resume command.")
-		return false
+// It returns ErrNotPaused if the TimeMachine is not in the PAUSED state.
+func (tm *TimeMachine) Resume() error {
+	if tm.State() != PAUSED {
+		return ErrNotPaused
 	}
 	tm.cmds <- "RESUME"
-	done := <-tm.done
-	if done {
-		return true
-	} else {
-		return false
-	}
+	return <-tm.done
 }
 
 // Stops the TimeMachine with the next cycle.
 //
-// It returns true when the TM has stopped and false otherwise.
-func (tm *TimeMachine) Stop() (success bool) {
-	if tm.state != RUNNING && tm.state != PAUSED {
-		log.Printf("TimeMachine is not in RUNNING or PAUSED state. Ignoring Stop command.")
-		return
+// It returns ErrAlreadyStopped if the TimeMachine is not in the RUNNING or PAUSED state.
+func (tm *TimeMachine) Stop() error {
+	state := tm.State()
+	if state != RUNNING && state != PAUSED {
+		return ErrAlreadyStopped
 	}
 	tm.cmds <- "STOP"
-	done := <-tm.done
-	if done {
-		return true
-	} else {
-		return false
-	}
+	return <-tm.done
 }
 
 // SetSpeed sets the speed of the simulation.
-// If the speed is smaller than 0.01 then it will be reset to 0.01.
-func (tm *TimeMachine) SetSpeed(speed float64) (success bool) {
+//
+// It returns ErrInvalidSpeed if speed is smaller than 0.01.
+func (tm *TimeMachine) SetSpeed(speed float64) error {
 	if speed < 0.01 {
-		log.Printf("Speed = %v must not be smaller than 0.01. It will be reset to 0.01.", speed)
-		speed = 0.01
+		return ErrInvalidSpeed
 	}
 	tm.cmds <- "SetSpeed " + strconv.FormatFloat(speed, 'f', -1, 64)
-	done := <-tm.done
-	if done {
-		return true
-	} else {
-		return false
-	}
+	return <-tm.done
 }
 
 func (tm *TimeMachine) run() {
-	if tm.state != IDLE {
-		tm.done <- false //signal that the command could not be started.
+	if tm.State() != IDLE {
+		tm.done <- ErrAlreadyStarted //signal that the command could not be started.
 		return
 	}
-	tm.state = RUNNING
-	tm.done <- true                                 //signal that the command has been started.
+	tm.setState(RUNNING)
 	tick := (time.Duration)(1000000 * tm.cycleTime) //cycleTime ms.
 	var tReal time.Time
 	var tReal_pause time.Time //timestamp of the last pause
 	var tReal_offset int64    // cumulated offset in ms due to pause and resume
-	ticker := time.NewTicker(tick)
-	tReal_start := time.Now() //the start in real time
+	ticker := tm.clock.NewTicker(tick)
+	tReal_start := tm.clock.Now() //the start in real time
+	tm.done <- nil                //signal that the command has been started, with the clock's ticker already live.
 main:
 	for {
-		tReal = <-ticker.C
-		//check for events and commands
-	eventLoop:
-		for {
-			select {
-			case ev := <-tm.events:
-				tm.eventQueue.Add(ev)
-			default:
-				break eventLoop
-			}
-		}
+		// A plain tReal = <-ticker.C() would starve tm.cmds whenever the
+		// clock is a SimulatedClock and the test has no further Advance
+		// queued: nothing would ever wake run() to notice a pending
+		// Pause/Resume/Stop/SetSpeed. Selecting on both lets a command be
+		// handled as soon as it arrives instead of waiting for the next tick.
 		select {
+		case <-ticker.C():
+			// The tick only serves as a wakeup; its own timestamp can lag
+			// behind the clock's current time (e.g. a SimulatedClock.Advance
+			// that crosses several ticks at once), so read Now() instead of
+			// trusting the value the ticker happened to deliver.
+			tReal = tm.clock.Now()
 		case cmd := <-tm.cmds:
+			tReal = tm.clock.Now()
 			if cmd == "PAUSE" {
-				if tm.state != RUNNING {
-					tm.done <- false
+				if tm.State() != RUNNING {
+					tm.done <- ErrNotRunning
 				} else {
-					tm.state = PAUSED
+					tm.setState(PAUSED)
 					tReal_pause = tReal
-					tm.done <- true
+					tm.done <- nil
 				}
 			} else if cmd == "STOP" {
 				ticker.Stop()
-				tm.state = STOPPED
-				tm.done <- true
+				tm.setState(STOPPED)
+				tm.done <- nil
 				break main
 			} else if cmd == "RESUME" {
-				if tm.state != PAUSED {
-					tm.done <- false
+				if tm.State() != PAUSED {
+					tm.done <- ErrNotPaused
 				} else {
-					tm.state = RUNNING
+					tm.setState(RUNNING)
 					tReal_offset += tReal.Sub(tReal_pause).Milliseconds()
-					tm.done <- true
+					tm.done <- nil
 				}
 			} else if strings.Fields(cmd)[0] == "SetSpeed" {
 				speed, err := strconv.ParseFloat(strings.Fields(cmd)[1], 64)
 				if err == nil {
-					tm.speed = speed
-					tm.done <- true
+					tm.setSpeed(speed)
+					tm.done <- nil
 				} else {
 					log.Printf("Could not convert the speed in command: %s", cmd)
-					tm.done <- false
+					tm.done <- fmt.Errorf("sima: could not parse speed in command %q: %w", cmd, err)
 				}
 			} else {
 				log.Printf("Could not recognize command: %s", cmd)
 			}
-		default:
+		}
+		//check for new events
+		for _, ev := range tm.events.drain() {
+			tm.eventQueue.Add(ev)
 		}
 		//run events from the "past" of the real time
 		dtReal := tReal.Sub(tReal_start).Milliseconds() - tReal_offset //time passed in ms since start minus offset
-		if tm.state == RUNNING {
+		if tm.State() == RUNNING {
+			speed := tm.Speed()
 			for {
 				tNext, ok := tm.eventQueue.NextT()
 				if !ok {
 					break //no more events
 				}
-				if tNext < float64(dtReal)*tm.speed {
+				if tNext < float64(dtReal)*speed {
 					tm.Step()
 				} else {
 					break
 				}
 			}
+			tm.notifyDrift(tm.T(), float64(dtReal))
 		}
 	}
 }
 
 // Step processes the next event.
+// Events that were cancelled via [Timer.Stop] are discarded without
+// advancing the simulation time.
 func (tm *TimeMachine) Step() {
-	ev := tm.eventQueue.Next()
-	tm.t = ev.T
-	ev.F()
-	//check for new events
 	for {
-		select {
-		case ev := <-tm.events:
-			tm.eventQueue.Add(ev)
-		default:
+		ev := tm.eventQueue.Next()
+		if ev == nil {
 			return
 		}
+		if !ev.MarkFired() {
+			continue //the event was cancelled before it could fire.
+		}
+		tm.setT(ev.T)
+		start := time.Now()
+		ev.F()
+		tm.notifyFire(tm.T(), time.Since(start))
+		break
+	}
+	//check for new events
+	for _, ev := range tm.events.drain() {
+		tm.eventQueue.Add(ev)
 	}
-
 }
@@ -1,8 +1,10 @@
 package sima
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"testing"
 	"time"
 )
@@ -18,51 +20,51 @@ func TestNewTimeMachine(t *testing.T) {
 	if tm.speed != 1.0 {
 		t.Errorf("Expected initial speed to be 1.0, got %v", tm.speed)
 	}
-	if len(tm.events) != 0 {
-		t.Errorf("Expected initial events channel to be empty, got %v", len(tm.events))
+	if len(tm.events.items) != 0 {
+		t.Errorf("Expected initial event inbox to be empty, got %v", len(tm.events.items))
 	}
 }
 
 func TestTimeMachineCommands(t *testing.T) {
 	tm := NewTimeMachine(1.0, 10, 2)
-	if !tm.Start() {
-		t.Error("Expected Start to return true")
+	if err := tm.Start(); err != nil {
+		t.Errorf("Expected Start to succeed, got %v", err)
 	}
-	if !tm.Pause() {
-		t.Error("Expected Pause to return true")
+	if err := tm.Pause(); err != nil {
+		t.Errorf("Expected Pause to succeed, got %v", err)
 	}
-	if !tm.Resume() {
-		t.Error("Expected Resume to return true")
+	if err := tm.Resume(); err != nil {
+		t.Errorf("Expected Resume to succeed, got %v", err)
 	}
-	if !tm.Pause() {
-		t.Error("Expected Pause to return true")
+	if err := tm.Pause(); err != nil {
+		t.Errorf("Expected Pause to succeed, got %v", err)
 	}
-	if !tm.Resume() {
-		t.Error("Expected Resume to return true")
+	if err := tm.Resume(); err != nil {
+		t.Errorf("Expected Resume to succeed, got %v", err)
 	}
-	if tm.Resume() {
-		t.Error("Expected Pause to return false")
+	if err := tm.Resume(); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("Expected Resume to return ErrNotPaused, got %v", err)
 	}
-	if tm.Resume() {
-		t.Error("Expected Resume to return fale")
+	if err := tm.Resume(); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("Expected Resume to return ErrNotPaused, got %v", err)
 	}
-	if !tm.Pause() {
-		t.Error("Expected Pause to return true")
+	if err := tm.Pause(); err != nil {
+		t.Errorf("Expected Pause to succeed, got %v", err)
 	}
-	if tm.Pause() {
-		t.Error("Expected Pause to return false")
+	if err := tm.Pause(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected Pause to return ErrNotRunning, got %v", err)
 	}
-	if tm.Pause() {
-		t.Error("Expected Pause to return false")
+	if err := tm.Pause(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected Pause to return ErrNotRunning, got %v", err)
 	}
-	if !tm.Stop() {
-		t.Error("Expected Stop to return true")
+	if err := tm.Stop(); err != nil {
+		t.Errorf("Expected Stop to succeed, got %v", err)
 	}
-	if tm.Resume() {
-		t.Error("Expected Resume to return false")
+	if err := tm.Resume(); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("Expected Resume to return ErrNotPaused, got %v", err)
 	}
-	if tm.Stop() {
-		t.Error("Expected Stop to return false")
+	if err := tm.Stop(); !errors.Is(err, ErrAlreadyStopped) {
+		t.Errorf("Expected Stop to return ErrAlreadyStopped, got %v", err)
 	}
 }
 func TestTimeMachinePauseResume(t *testing.T) {
@@ -179,6 +181,84 @@ func TestTimeMachineSchedule(t *testing.T) {
 	tm.Stop()
 }
 
+func TestTimeMachineScheduleCancel(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 2)
+	tm.Start()
+	executed := false
+	timer := tm.Schedule(100, func() {
+		executed = true
+	})
+	if !timer.Stop() {
+		t.Error("Expected Stop to return true for a pending timer")
+	}
+	if timer.Stop() {
+		t.Error("Expected Stop to return false for an already stopped timer")
+	}
+	time.Sleep(200 * time.Millisecond) // wait for the event to have been skipped
+	if executed {
+		t.Errorf("Expected cancelled event NOT to be executed")
+	}
+	tm.Stop()
+}
+
+func TestTimeMachineScheduleReset(t *testing.T) {
+	tm := NewTimeMachine(1.0, 10, 2)
+	tm.Start()
+	count := 0
+	var timer *Timer
+	timer = tm.Schedule(100, func() {
+		count++
+	})
+	if !timer.Reset(10) {
+		t.Error("Expected Reset to return true for a pending timer")
+	}
+	time.Sleep(200 * time.Millisecond) // wait for the rescheduled event to fire
+	if count != 1 {
+		t.Errorf("Expected the rescheduled event to fire exactly once, got %v", count)
+	}
+	tm.Stop()
+}
+
+// TestTimeMachineConcurrentSchedule hammers Schedule from many goroutines
+// while another goroutine repeatedly pauses, resumes and changes the speed,
+// to be run with -race.
+func TestTimeMachineConcurrentSchedule(t *testing.T) {
+	tm := NewTimeMachine(10.0, 10, 1)
+	tm.Start()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tm.Schedule(float64(j), func() {})
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				tm.Pause()
+				_ = tm.T()
+				_ = tm.State()
+				_ = tm.Speed()
+				tm.SetSpeed(5.0)
+				tm.Resume()
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	tm.Stop()
+}
+
 // Example shows how to use the TimeMachine.
 // Before Start the ping function is scheduled at t=0 ms.
 // The TimeMachine then runs ping and schedules pong at t=20 ms.
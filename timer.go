@@ -0,0 +1,61 @@
+package sima
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/jcderner/sima/internal/core"
+)
+
+// Timer represents a single pending event scheduled via [TimeMachine.Schedule].
+// It mirrors the semantics of [time.Timer], allowing the event to be
+// cancelled or rescheduled before it fires.
+//
+// A Timer returned by [TimeMachine.SchedulePeriodic] re-arms itself every
+// period until Stop is called.
+type Timer struct {
+	tm      *TimeMachine
+	event   *core.Event
+	f       func()
+	period  float64 //non-zero for timers created by SchedulePeriodic.
+	stopped atomic.Bool
+}
+
+// Stop cancels the timer, preventing its function from running, and (for a
+// periodic timer) any future re-firings.
+//
+// It returns true if the call stops the timer, false if the timer has
+// already fired or was already stopped.
+func (t *Timer) Stop() bool {
+	t.stopped.Store(true)
+	wasPending := t.event.Cancel()
+	if wasPending {
+		t.tm.notifyCancel(t.event.T)
+	}
+	return wasPending
+}
+
+// Reset cancels the previous event, if still pending, and schedules f to run
+// again dt ms after the current simulation time.
+//
+// It returns true if the previous event was still pending before being
+// rescheduled.
+func (t *Timer) Reset(dt float64) bool {
+	wasPending := t.event.Cancel()
+	if wasPending {
+		t.tm.notifyCancel(t.event.T)
+	}
+	if dt < 0 {
+		log.Printf("dt = %v < 0. Will be reset to 0.0", dt)
+		dt = 0.0
+	}
+	f := t.f
+	if t.period != 0 {
+		f = t.firePeriodic //keep a periodic timer re-arming after Reset.
+	}
+	t.tm.notifySchedule(t.tm.T(), dt)
+	ev := &core.Event{T: t.tm.T() + dt, F: f}
+	t.event = ev
+	t.tm.events.push(ev)
+	return wasPending
+}
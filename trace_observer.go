@@ -0,0 +1,66 @@
+package sima
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// traceRecord is one JSONL record written by a [JSONLTraceObserver].
+type traceRecord struct {
+	Type     string        `json:"type"`
+	T        float64       `json:"t,omitempty"`
+	Dt       float64       `json:"dt,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Old      string        `json:"old,omitempty"`
+	New      string        `json:"new,omitempty"`
+	SimT     float64       `json:"simT,omitempty"`
+	RealT    float64       `json:"realT,omitempty"`
+}
+
+// JSONLTraceObserver is an [Observer] that writes one JSON record per
+// notification to w, for offline analysis of a simulation run.
+type JSONLTraceObserver struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLTraceObserver creates a JSONLTraceObserver writing to w.
+func NewJSONLTraceObserver(w io.Writer) *JSONLTraceObserver {
+	return &JSONLTraceObserver{enc: json.NewEncoder(w)}
+}
+
+func (j *JSONLTraceObserver) write(r traceRecord) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(r); err != nil {
+		log.Printf("JSONLTraceObserver: could not write trace record: %v", err)
+	}
+}
+
+// OnSchedule implements [Observer].
+func (j *JSONLTraceObserver) OnSchedule(t, dt float64) {
+	j.write(traceRecord{Type: "schedule", T: t, Dt: dt})
+}
+
+// OnFire implements [Observer].
+func (j *JSONLTraceObserver) OnFire(t float64, dur time.Duration) {
+	j.write(traceRecord{Type: "fire", T: t, Duration: dur})
+}
+
+// OnCancel implements [Observer].
+func (j *JSONLTraceObserver) OnCancel(t float64) {
+	j.write(traceRecord{Type: "cancel", T: t})
+}
+
+// OnStateChange implements [Observer].
+func (j *JSONLTraceObserver) OnStateChange(old, new TMState) {
+	j.write(traceRecord{Type: "stateChange", Old: old.String(), New: new.String()})
+}
+
+// OnDrift implements [Observer].
+func (j *JSONLTraceObserver) OnDrift(simT, realT float64) {
+	j.write(traceRecord{Type: "drift", SimT: simT, RealT: realT})
+}